@@ -0,0 +1,225 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry records what's known about a previously fetched URL, enough
+// to skip a redundant download or resume a partial one.
+type CacheEntry struct {
+	ETag         string
+	LastModified string
+
+	// ContentSHA256 is the digest of the fully downloaded content at Path.
+	ContentSHA256 string
+
+	// Path is where the (possibly partial) download is staged on disk.
+	Path string
+
+	// BytesWritten is how much of Path has been written so far; a
+	// fetch that died partway through resumes from here with a Range
+	// request instead of starting over.
+	BytesWritten int64
+
+	UpdatedAt time.Time
+}
+
+// Cache stores CacheEntry metadata keyed by an opaque string (HTTPFetcher
+// uses sha256(url)), so repeated polls of an unchanged upstream don't
+// re-download the full archive every tick.
+type Cache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry) error
+	Delete(key string) error
+
+	// GC removes entries whose UpdatedAt is older than ttl.
+	GC(ttl time.Duration) error
+
+	// DataPath returns a filesystem path a caller can stage key's data
+	// at, creating any directories it needs. Every Cache implementation
+	// must back this with real disk, even a memory-backed one, since
+	// resumable downloads need a file to write partial progress to
+	// between calls.
+	DataPath(key string) (string, error)
+}
+
+// cacheKey derives a Cache key from a URL.
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// DirCache is the default Cache: one JSON metadata file plus one data file
+// per key, under Dir. Its methods are safe to call concurrently, so
+// multiple Updaters in the same process can share one DirCache; the lock
+// only covers this process, not other processes that might point a Cache
+// at the same Dir.
+type DirCache struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewDirCache creates a DirCache rooted at dir, creating it if necessary.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirCache{Dir: dir}, nil
+}
+
+// DataPath implements Cache.
+func (c *DirCache) DataPath(key string) (string, error) {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(c.Dir, key+".data"), nil
+}
+
+func (c *DirCache) metaPath(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Get implements Cache.
+func (c *DirCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := ioutil.ReadFile(c.metaPath(key))
+	if err != nil {
+		return CacheEntry{}, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Set implements Cache.
+func (c *DirCache) Set(key string, entry CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.metaPath(key), b, 0o644)
+}
+
+// Delete implements Cache.
+func (c *DirCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	os.Remove(c.metaPath(key))
+	if path, err := c.DataPath(key); err == nil {
+		os.Remove(path)
+	}
+	return nil
+}
+
+// GC implements Cache.
+func (c *DirCache) GC(ttl time.Duration) error {
+	files, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		key := strings.TrimSuffix(f.Name(), ".json")
+		entry, ok := c.Get(key)
+		if !ok || entry.UpdatedAt.Before(cutoff) {
+			c.Delete(key)
+		}
+	}
+	return nil
+}
+
+// MemCache is an in-memory Cache, useful in tests or short-lived processes
+// where persisting metadata across runs doesn't matter. It still stages
+// downloaded data on disk, in a temp directory it owns, since a resumable
+// download needs a real file to write partial progress to between calls.
+type MemCache struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	dataDir string
+}
+
+// NewMemCache creates an empty MemCache.
+func NewMemCache() *MemCache {
+	return &MemCache{entries: make(map[string]CacheEntry)}
+}
+
+// Get implements Cache.
+func (c *MemCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// Set implements Cache.
+func (c *MemCache) Set(key string, entry CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+// Delete implements Cache.
+func (c *MemCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+// GC implements Cache.
+func (c *MemCache) GC(ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cutoff := time.Now().Add(-ttl)
+	for key, entry := range c.entries {
+		if entry.UpdatedAt.Before(cutoff) {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// DataPath implements Cache.
+func (c *MemCache) DataPath(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dataDir == "" {
+		dir, err := ioutil.TempDir("", "updater-memcache")
+		if err != nil {
+			return "", err
+		}
+		c.dataDir = dir
+	}
+	return filepath.Join(c.dataDir, key+".data"), nil
+}
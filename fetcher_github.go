@@ -0,0 +1,204 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AssetMatchFunc decides whether a release asset is the right one for the
+// running platform. The default matches an asset name containing both
+// runtime.GOOS and runtime.GOARCH.
+type AssetMatchFunc func(assetName string) bool
+
+// DefaultAssetMatch matches asset names containing the current GOOS and
+// GOARCH, e.g. "myapp-linux-amd64.zip".
+func DefaultAssetMatch(assetName string) bool {
+	name := strings.ToLower(assetName)
+	return strings.Contains(name, runtime.GOOS) && strings.Contains(name, runtime.GOARCH)
+}
+
+// GitHubFetcher fetches the latest release archive from a GitHub Releases
+// page, picking the asset that matches the running platform.
+type GitHubFetcher struct {
+	User  string
+	Repo  string
+	Match AssetMatchFunc
+
+	// CurrentVersion is compared against the release's tag to reject
+	// downgrades. Leave empty to accept any release.
+	CurrentVersion string
+
+	Client   *http.Client
+	interval time.Duration
+
+	etag string
+
+	// apiBaseURL overrides the GitHub API origin; tests set it to point
+	// Fetch at an httptest.Server instead of the real api.github.com.
+	apiBaseURL string
+}
+
+const githubAPIBaseURL = "https://api.github.com"
+
+type githubRelease struct {
+	TagName string               `json:"tag_name"`
+	Assets  []githubReleaseAsset `json:"assets"`
+}
+
+type githubReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// NewGitHubFetcher creates a GitHubFetcher polling the latest release of
+// user/repo every interval, matching assets with DefaultAssetMatch.
+func NewGitHubFetcher(user, repo string, interval time.Duration) *GitHubFetcher {
+	return &GitHubFetcher{
+		User:     user,
+		Repo:     repo,
+		Match:    DefaultAssetMatch,
+		Client:   http.DefaultClient,
+		interval: interval,
+	}
+}
+
+// Interval implements Fetcher.
+func (f *GitHubFetcher) Interval() time.Duration {
+	return f.interval
+}
+
+// Fetch implements Fetcher.
+func (f *GitHubFetcher) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	base := f.apiBaseURL
+	if base == "" {
+		base = githubAPIBaseURL
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/latest", base, f.User, f.Repo)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("updater: github releases returned %s", resp.Status)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+
+	if f.CurrentVersion != "" {
+		newer, err := semverGreater(release.TagName, f.CurrentVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !newer {
+			f.etag = resp.Header.Get("ETag")
+			return nil, ErrNotModified
+		}
+	}
+
+	match := f.Match
+	if match == nil {
+		match = DefaultAssetMatch
+	}
+
+	var assetURL string
+	for _, a := range release.Assets {
+		if match(a.Name) {
+			assetURL = a.BrowserDownloadURL
+			break
+		}
+	}
+	if assetURL == "" {
+		return nil, fmt.Errorf("updater: no release asset matches %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	assetReq, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	assetResp, err := client.Do(assetReq)
+	if err != nil {
+		return nil, err
+	}
+	if assetResp.StatusCode != http.StatusOK {
+		assetResp.Body.Close()
+		return nil, fmt.Errorf("updater: asset download returned %s", assetResp.Status)
+	}
+
+	f.etag = resp.Header.Get("ETag")
+	return withSize(assetResp.Body, assetResp.ContentLength), nil
+}
+
+// semverGreater reports whether a is a greater semver than b. Both may be
+// prefixed with "v". It only understands the MAJOR.MINOR.PATCH form; any
+// extra pre-release/build metadata is ignored in the comparison.
+func semverGreater(a, b string) (bool, error) {
+	av, err := parseSemver(a)
+	if err != nil {
+		return false, err
+	}
+	bv, err := parseSemver(b)
+	if err != nil {
+		return false, err
+	}
+
+	for i := 0; i < 3; i++ {
+		if av[i] != bv[i] {
+			return av[i] > bv[i], nil
+		}
+	}
+	return false, nil
+}
+
+func parseSemver(v string) ([3]int, error) {
+	var out [3]int
+
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) == 0 {
+		return out, errors.New("updater: invalid semver " + v)
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, fmt.Errorf("updater: invalid semver %q: %w", v, err)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
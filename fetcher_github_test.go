@@ -0,0 +1,165 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestSemverGreater(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.3", "1.2.2", true},
+		{"1.2.2", "1.2.3", false},
+		{"1.2.3", "1.2.3", false},
+		{"v1.2.3", "1.2.2", true},
+		{"2.0.0", "1.9.9", true},
+		{"1.2.3-rc1", "1.2.2", true},
+		{"1.2.3", "1.2.3-rc1", false}, // the suffix is ignored, so these compare equal
+		{"1.2", "1.1.9", true},        // missing components default to 0
+		{"1.2", "1.2.0", false},
+	}
+
+	for _, tt := range tests {
+		got, err := semverGreater(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("semverGreater(%q, %q) returned error: %v", tt.a, tt.b, err)
+		}
+		if got != tt.want {
+			t.Errorf("semverGreater(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSemverGreaterRejectsNonNumericComponent(t *testing.T) {
+	if _, err := semverGreater("1.2.x", "1.2.0"); err == nil {
+		t.Fatal("semverGreater() = nil error, want error for a non-numeric component")
+	}
+}
+
+func TestDefaultAssetMatch(t *testing.T) {
+	name := fmt.Sprintf("myapp-%s-%s.zip", runtime.GOOS, runtime.GOARCH)
+	if !DefaultAssetMatch(name) {
+		t.Fatalf("DefaultAssetMatch(%q) = false, want true", name)
+	}
+	if DefaultAssetMatch("myapp-some-other-platform.zip") {
+		t.Fatal("DefaultAssetMatch() = true for an asset matching neither GOOS nor GOARCH, want false")
+	}
+}
+
+func newTestGitHubFetcher(baseURL string) *GitHubFetcher {
+	return &GitHubFetcher{
+		User:       "acme",
+		Repo:       "widget",
+		Match:      DefaultAssetMatch,
+		Client:     http.DefaultClient,
+		apiBaseURL: baseURL,
+	}
+}
+
+func TestGitHubFetcherConditionalRequestReturnsNotModified(t *testing.T) {
+	var releaseCalls int
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/repos/acme/widget/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		releaseCalls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprintf(w, `{"tag_name":"1.0.0","assets":[{"name":"myapp-match.zip","browser_download_url":%q}]}`, srv.URL+"/asset")
+	})
+	mux.HandleFunc("/asset", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("archive-bytes"))
+	})
+
+	f := newTestGitHubFetcher(srv.URL)
+	f.Match = func(assetName string) bool { return assetName == "myapp-match.zip" }
+
+	rc, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("first Fetch() = %v, want nil", err)
+	}
+	rc.Close()
+
+	if _, err := f.Fetch(context.Background()); err != ErrNotModified {
+		t.Fatalf("second Fetch() err = %v, want ErrNotModified", err)
+	}
+	if releaseCalls != 2 {
+		t.Fatalf("server got %d release requests, want 2", releaseCalls)
+	}
+}
+
+func TestGitHubFetcherRejectsDowngrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"1.9.0","assets":[]}`))
+	}))
+	defer srv.Close()
+
+	f := newTestGitHubFetcher(srv.URL)
+	f.CurrentVersion = "2.0.0"
+
+	if _, err := f.Fetch(context.Background()); err != ErrNotModified {
+		t.Fatalf("Fetch() err = %v, want ErrNotModified for a release older than CurrentVersion", err)
+	}
+}
+
+func TestGitHubFetcherDownloadsMatchingAsset(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/repos/acme/widget/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"2.0.0","assets":[
+			{"name":"myapp-other.zip","browser_download_url":%q},
+			{"name":"myapp-match.zip","browser_download_url":%q}
+		]}`, srv.URL+"/asset-other", srv.URL+"/asset-match")
+	})
+	mux.HandleFunc("/asset-other", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("wrong-asset"))
+	})
+	mux.HandleFunc("/asset-match", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("right-asset"))
+	})
+
+	f := newTestGitHubFetcher(srv.URL)
+	f.CurrentVersion = "1.0.0"
+	f.Match = func(assetName string) bool { return assetName == "myapp-match.zip" }
+
+	rc, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() = %v, want nil", err)
+	}
+	defer rc.Close()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "right-asset" {
+		t.Fatalf("Fetch() body = %q, want %q", got, "right-asset")
+	}
+}
+
+func TestGitHubFetcherErrorsWhenNoAssetMatches(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"2.0.0","assets":[{"name":"myapp-other.zip","browser_download_url":"http://example.invalid/a"}]}`))
+	}))
+	defer srv.Close()
+
+	f := newTestGitHubFetcher(srv.URL)
+	f.Match = func(assetName string) bool { return false }
+
+	if _, err := f.Fetch(context.Background()); err == nil {
+		t.Fatal("Fetch() = nil, want error when no asset matches")
+	}
+}
@@ -0,0 +1,77 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// S3Fetcher fetches the update archive from an S3-compatible bucket/key.
+// It issues a plain signed-URL-free GET against the object; callers that
+// need request signing should set Client to an *http.Client whose
+// Transport attaches the appropriate Authorization headers.
+type S3Fetcher struct {
+	Bucket string
+	Key    string
+	Region string
+
+	Client   *http.Client
+	interval time.Duration
+
+	etag string
+}
+
+// NewS3Fetcher creates an S3Fetcher for the given bucket/key, polling every interval.
+func NewS3Fetcher(bucket, key, region string, interval time.Duration) *S3Fetcher {
+	return &S3Fetcher{
+		Bucket:   bucket,
+		Key:      key,
+		Region:   region,
+		Client:   http.DefaultClient,
+		interval: interval,
+	}
+}
+
+// Interval implements Fetcher.
+func (f *S3Fetcher) Interval() time.Duration {
+	return f.interval
+}
+
+func (f *S3Fetcher) url() string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", f.Bucket, f.Region, f.Key)
+}
+
+// Fetch implements Fetcher.
+func (f *S3Fetcher) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("updater: s3 fetch returned %s", resp.Status)
+	}
+
+	f.etag = resp.Header.Get("ETag")
+	return withSize(resp.Body, resp.ContentLength), nil
+}
@@ -0,0 +1,72 @@
+package updater
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPFetcherUsesMemCacheNotCWD(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("archive-bytes"))
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher(srv.URL, 0)
+	f.Cache = NewMemCache()
+
+	rc, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "archive-bytes" {
+		t.Fatalf("Fetch() = %q, want %q", got, "archive-bytes")
+	}
+
+	entry, ok := f.Cache.Get(cacheKey(srv.URL))
+	if !ok {
+		t.Fatal("expected a cache entry after fetch")
+	}
+	if entry.Path == "" {
+		t.Fatal("cache entry has no staged path")
+	}
+}
+
+func TestHTTPFetcherConditionalRequestReturnsNotModified(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("archive-bytes"))
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher(srv.URL, 0)
+	f.Cache = NewMemCache()
+
+	rc, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	if _, err := f.Fetch(context.Background()); err != ErrNotModified {
+		t.Fatalf("second Fetch() err = %v, want ErrNotModified", err)
+	}
+	if calls != 2 {
+		t.Fatalf("server got %d requests, want 2", calls)
+	}
+}
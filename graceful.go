@@ -0,0 +1,418 @@
+//go:build !windows
+
+// The supervisor/worker protocol below leans on Unix-only primitives
+// (SCM_RIGHTS fd passing over a socketpair, SIGTERM) to hand listeners
+// between generations; see graceful_windows.go for the Windows fallback.
+
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Graceful restart protocol: a tiny supervisor process execs a worker
+// (the one that actually calls the user's Run function) and keeps three
+// extra files connected to it, always in this order:
+//
+//	3: readyW   - worker writes one byte once it's ready to serve
+//	4: triggerW - worker writes one byte to ask the supervisor for a restart
+//	5: handoff  - unix socketpair the worker uses to hand its listeners
+//	              back to the supervisor, so the *next* worker can inherit them
+//	6..: inherited net.Listener files, in Handoff order
+const (
+	envGeneration = "UPDATER_GENERATION"
+	envNumFDs     = "UPDATER_NUM_FDS"
+
+	fdReady    = 3
+	fdTrigger  = 4
+	fdHandoff  = 5
+	fdListener = 6
+)
+
+// DrainTimeout is how long the supervisor waits for the previous generation
+// to exit on its own after SIGTERM before sending SIGKILL.
+var DrainTimeout = 15 * time.Second
+
+// ReadyTimeout is how long the supervisor waits for a newly spawned
+// generation to signal readiness before giving up on the restart.
+var ReadyTimeout = 30 * time.Second
+
+// State describes a process's place in the graceful-restart lineage. It's
+// handed to the function passed to Run.
+type State struct {
+	// Generation increments by one on every restart; the first process
+	// Run ever starts is generation 0.
+	Generation int
+
+	// FirstStart is true only for generation 0, i.e. there are no
+	// listeners to inherit.
+	FirstStart bool
+
+	// Listeners were inherited from the previous generation. Generation 0
+	// always has none; later generations get back whatever was passed to
+	// Handoff by the generation before them.
+	Listeners []net.Listener
+
+	readyW   *os.File
+	triggerW *os.File
+	handoff  *net.UnixConn
+}
+
+// Ready signals the supervisor that this generation has finished starting
+// up and is safe to route traffic to. The supervisor waits for this before
+// retiring the previous generation.
+func (s State) Ready() error {
+	if s.readyW == nil {
+		return nil
+	}
+	_, err := s.readyW.Write([]byte{1})
+	return err
+}
+
+// Handoff registers listeners with the supervisor so that the next
+// generation (spawned on the next RestartAndUpdate) can inherit them
+// instead of binding fresh sockets. Call it once, after creating any
+// listeners this generation owns.
+func (s State) Handoff(listeners []net.Listener) error {
+	if s.handoff == nil || len(listeners) == 0 {
+		return nil
+	}
+
+	files := make([]*os.File, len(listeners))
+	for i, l := range listeners {
+		f, err := listenerFile(l)
+		if err != nil {
+			return fmt.Errorf("updater: handoff listener %d: %w", i, err)
+		}
+		files[i] = f
+	}
+
+	rights := syscall.UnixRights(filesFDs(files)...)
+	_, _, err := s.handoff.WriteMsgUnix([]byte{byte(len(files))}, rights, nil)
+	return err
+}
+
+// Restart asks the supervisor to start the next generation in the
+// background. It does not block until the new generation is ready; use
+// RollbackOnFailure-style checks in user code if that matters.
+func (s State) Restart() error {
+	if s.triggerW == nil {
+		return errors.New("updater: Restart called outside of a supervised process")
+	}
+	_, err := s.triggerW.Write([]byte{1})
+	return err
+}
+
+func listenerFile(l net.Listener) (*os.File, error) {
+	type fileListener interface {
+		File() (*os.File, error)
+	}
+	fl, ok := l.(fileListener)
+	if !ok {
+		return nil, fmt.Errorf("updater: listener of type %T can't be shared across processes", l)
+	}
+	return fl.File()
+}
+
+func filesFDs(files []*os.File) []int {
+	fds := make([]int, len(files))
+	for i, f := range files {
+		fds[i] = int(f.Fd())
+	}
+	return fds
+}
+
+// Run is the graceful-restart entry point. Call it once from main with the
+// function that runs the program. The very first call becomes a tiny
+// supervisor: it execs a copy of the binary as generation 0 and re-execs
+// new generations on demand, without ever running fn itself. Every
+// re-exec'd process runs fn with a State describing its generation and any
+// listeners inherited from the previous one.
+func Run(ctx context.Context, fn func(ctx context.Context, state State)) error {
+	if gen, ok := os.LookupEnv(envGeneration); ok {
+		return runWorker(ctx, gen, fn)
+	}
+	return runSupervisor(ctx)
+}
+
+// runWorker is executed inside a generation's process.
+func runWorker(ctx context.Context, genStr string, fn func(context.Context, State)) error {
+	gen, err := strconv.Atoi(genStr)
+	if err != nil {
+		return fmt.Errorf("updater: invalid %s: %w", envGeneration, err)
+	}
+
+	numFDs, _ := strconv.Atoi(os.Getenv(envNumFDs))
+
+	state := State{
+		Generation: gen,
+		FirstStart: gen == 0,
+		readyW:     os.NewFile(fdReady, "updater-ready"),
+		triggerW:   os.NewFile(fdTrigger, "updater-trigger"),
+	}
+
+	if handoffFile := os.NewFile(fdHandoff, "updater-handoff"); handoffFile != nil {
+		if conn, err := net.FileConn(handoffFile); err == nil {
+			if uc, ok := conn.(*net.UnixConn); ok {
+				state.handoff = uc
+			}
+		}
+	}
+
+	for i := 0; i < numFDs; i++ {
+		f := os.NewFile(uintptr(fdListener+i), "updater-listener")
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("updater: inherit listener %d: %w", i, err)
+		}
+		state.Listeners = append(state.Listeners, l)
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	currentState = &state
+	fn(workerCtx, state)
+	return nil
+}
+
+// currentState is set once Run starts a worker, so Updater.RestartAndUpdate
+// can ask the supervisor for a graceful restart instead of falling back to
+// the plain rename-and-kill dance.
+var currentState *State
+
+// runSupervisor never runs fn; it only manages the lineage of workers.
+func runSupervisor(ctx context.Context) error {
+	sup := &supervisor{}
+	current, err := sup.spawn(0, nil)
+	if err != nil {
+		return err
+	}
+	sup.current = current
+
+	if err := sup.awaitReady(current); err != nil {
+		return fmt.Errorf("updater: generation 0 never became ready: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return sup.current.cmd.Process.Signal(syscall.SIGTERM)
+		case err := <-sup.current.exited:
+			return err
+		case listeners := <-sup.current.handedOff:
+			sup.listeners = listeners
+		case <-sup.current.triggered:
+			if err := sup.restart(); err != nil {
+				// Keep the current generation running; a failed restart
+				// attempt shouldn't take the service down.
+				continue
+			}
+		}
+	}
+}
+
+// worker is the supervisor's view of one generation's process.
+type worker struct {
+	generation int
+	cmd        *exec.Cmd
+	readyR     *os.File
+	triggerR   *os.File
+	handoffFd  *os.File
+
+	exited    chan error
+	triggered chan struct{}
+	handedOff chan []*os.File
+}
+
+type supervisor struct {
+	current   *worker
+	listeners []*os.File
+}
+
+func (s *supervisor) spawn(generation int, listeners []*os.File) (*worker, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	triggerR, triggerW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	handoffParent, handoffChild, err := socketpair()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(execPath, os.Args[1:]...)
+	cmd.Dir = ""
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envGeneration, generation),
+		fmt.Sprintf("%s=%d", envNumFDs, len(listeners)),
+	)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append([]*os.File{readyW, triggerW, handoffChild}, listeners...)
+
+	if err := cmd.Start(); err != nil {
+		readyR.Close()
+		readyW.Close()
+		triggerR.Close()
+		triggerW.Close()
+		handoffParent.Close()
+		handoffChild.Close()
+		return nil, err
+	}
+
+	// The child now owns its ends; the supervisor only needs the read/parent ends.
+	readyW.Close()
+	triggerW.Close()
+	handoffChild.Close()
+
+	w := &worker{
+		generation: generation,
+		cmd:        cmd,
+		readyR:     readyR,
+		triggerR:   triggerR,
+		handoffFd:  handoffParent,
+		exited:     make(chan error, 1),
+		triggered:  make(chan struct{}, 1),
+		handedOff:  make(chan []*os.File, 1),
+	}
+
+	go func() {
+		w.exited <- cmd.Wait()
+	}()
+	go w.watchTrigger()
+	go w.watchHandoff()
+
+	return w, nil
+}
+
+func (w *worker) watchTrigger() {
+	buf := make([]byte, 1)
+	for {
+		if _, err := w.triggerR.Read(buf); err != nil {
+			return
+		}
+		select {
+		case w.triggered <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *worker) watchHandoff() {
+	conn, err := net.FileConn(w.handoffFd)
+	if err != nil {
+		return
+	}
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return
+	}
+
+	for {
+		buf := make([]byte, 1)
+		oob := make([]byte, 64)
+		_, oobn, _, _, err := uc.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return
+		}
+
+		scms, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			continue
+		}
+		var files []*os.File
+		for _, scm := range scms {
+			fds, err := syscall.ParseUnixRights(&scm)
+			if err != nil {
+				continue
+			}
+			for _, fd := range fds {
+				files = append(files, os.NewFile(uintptr(fd), "updater-handoff-listener"))
+			}
+		}
+		if len(files) > 0 {
+			w.handedOff <- files
+		}
+	}
+}
+
+func (s *supervisor) awaitReady(w *worker) error {
+	done := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := w.readyR.Read(buf)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(ReadyTimeout):
+		return errors.New("updater: timed out waiting for readiness")
+	}
+}
+
+// restart spawns the next generation, waits for it to become ready, then
+// retires the current generation.
+func (s *supervisor) restart() error {
+	next, err := s.spawn(s.current.generation+1, s.listeners)
+	if err != nil {
+		return err
+	}
+
+	if err := s.awaitReady(next); err != nil {
+		next.cmd.Process.Kill()
+		return err
+	}
+
+	old := s.current
+	s.current = next
+
+	old.cmd.Process.Signal(syscall.SIGTERM)
+	go func() {
+		select {
+		case <-old.exited:
+		case <-time.After(DrainTimeout):
+			old.cmd.Process.Kill()
+		}
+	}()
+
+	return nil
+}
+
+func socketpair() (*os.File, *os.File, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return os.NewFile(uintptr(fds[0]), "updater-handoff-a"),
+		os.NewFile(uintptr(fds[1]), "updater-handoff-b"), nil
+}
@@ -0,0 +1,52 @@
+//go:build !windows
+
+package updater
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFilesFDsReturnsEachFileDescriptor(t *testing.T) {
+	files := make([]*os.File, 3)
+	for i := range files {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		defer w.Close()
+		files[i] = w
+	}
+
+	fds := filesFDs(files)
+	if len(fds) != len(files) {
+		t.Fatalf("filesFDs() returned %d fds, want %d", len(fds), len(files))
+	}
+	for i, f := range files {
+		if fds[i] != int(f.Fd()) {
+			t.Fatalf("filesFDs()[%d] = %d, want %d", i, fds[i], f.Fd())
+		}
+	}
+}
+
+func TestStateRestartOutsideSupervisorReturnsError(t *testing.T) {
+	var s State
+	if err := s.Restart(); err == nil {
+		t.Fatal("Restart() = nil, want error when not running under a supervisor")
+	}
+}
+
+func TestStateReadyIsNoopWithoutSupervisor(t *testing.T) {
+	var s State
+	if err := s.Ready(); err != nil {
+		t.Fatalf("Ready() = %v, want nil when not running under a supervisor", err)
+	}
+}
+
+func TestStateHandoffIsNoopWithoutListeners(t *testing.T) {
+	var s State
+	if err := s.Handoff(nil); err != nil {
+		t.Fatalf("Handoff(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package updater
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DrainTimeout and ReadyTimeout exist on Windows for API parity, but the
+// supervisor/worker split they configure (see graceful.go) relies on
+// Unix-only fd passing and isn't available here.
+var (
+	DrainTimeout = 15 * time.Second
+	ReadyTimeout = 30 * time.Second
+)
+
+// State describes a process's place in the graceful-restart lineage. On
+// Windows there is currently no supervisor process, so every process is
+// generation 0 with no inherited listeners; RestartAndUpdate instead uses
+// the service-control-manager or fresh-process dance in swap_windows.go.
+type State struct {
+	Generation int
+	FirstStart bool
+	Listeners  []net.Listener
+}
+
+// Ready is a no-op on Windows; there's no supervisor to notify.
+func (s State) Ready() error { return nil }
+
+// Handoff is a no-op on Windows; listeners aren't inherited across restarts.
+func (s State) Handoff(listeners []net.Listener) error { return nil }
+
+// Restart always returns an error on Windows, since there's no supervisor
+// process to ask for a graceful handoff; the error names
+// Updater.RestartAndUpdate, which does not go through Restart here.
+func (s State) Restart() error {
+	return errNoSupervisor
+}
+
+var errNoSupervisor = &unsupportedError{"graceful restart requires a supervisor process, which isn't implemented on Windows; call Updater.RestartAndUpdate instead"}
+
+type unsupportedError struct{ msg string }
+
+func (e *unsupportedError) Error() string { return "updater: " + e.msg }
+
+// currentState is always nil on Windows, so Updater.RestartAndUpdate falls
+// straight through to swapAndRestart.
+var currentState *State
+
+// Run simply calls fn with generation 0; see the type doc on State for why
+// there's no re-exec supervision on this platform.
+func Run(ctx context.Context, fn func(ctx context.Context, state State)) error {
+	fn(ctx, State{FirstStart: true})
+	return nil
+}
@@ -5,4 +5,31 @@ type Info struct {
 	Name        string `json:"name"`
 	Version     string `json:"version"`
 	Description string `json:"description"`
+
+	// SHA256 is the hex-encoded digest of the full binary this Info
+	// describes. Patch application verifies against it, and signature
+	// verification is computed over it.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Signature is a base64-encoded signature over sha256(binary) ||
+	// version, and SignatureAlgorithm names the scheme used to produce
+	// it. SignatureAlgorithm defaults to "ed25519" when empty.
+	Signature          string `json:"signature,omitempty"`
+	SignatureAlgorithm string `json:"signature_algorithm,omitempty"`
+
+	// Patches maps the hex-encoded sha256 digest of a client's
+	// currently-running binary (not a version string) to the diff that
+	// upgrades it to Version, so clients can fetch a small patch instead
+	// of the full archive. See Updater.updateViaPatch, which looks up
+	// its own binary's digest in this map.
+	Patches map[string]PatchInfo `json:"patches,omitempty"`
+}
+
+// PatchInfo describes a binary diff (as produced by bsdiff) that upgrades
+// a client whose running binary has the digest it's keyed by in
+// Info.Patches to the release Info it's attached to.
+type PatchInfo struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
 }
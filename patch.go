@@ -0,0 +1,121 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/kr/binarydist"
+)
+
+// PatchStrategy lets an Updater fetch a binary diff instead of a full
+// archive when the upstream advertises one applicable to the running
+// binary. Set Updater.Patch to opt in; leave it nil to always download the
+// full archive via the Fetcher.
+type PatchStrategy interface {
+	// Manifest returns the latest Info available upstream, without
+	// downloading the full update archive.
+	Manifest(ctx context.Context) (Info, error)
+
+	// FetchPatch downloads the patch described by p.
+	FetchPatch(ctx context.Context, p PatchInfo) (io.ReadCloser, error)
+}
+
+// updateViaPatch tries to bring execPath up to date with a bsdiff patch
+// instead of a full download. It returns an error for any reason the
+// caller should fall back to the full archive: no patch is available for
+// the running binary, the download failed, or the patched result didn't
+// match the expected hash.
+func (u *Updater) updateViaPatch(ctx context.Context) (Info, error) {
+	manifest, err := u.Patch.Manifest(ctx)
+	if err != nil {
+		return Info{}, err
+	}
+
+	currentSHA, err := sha256File(u.execPath)
+	if err != nil {
+		return Info{}, err
+	}
+
+	patch, ok := manifest.Patches[currentSHA]
+	if !ok {
+		return Info{}, fmt.Errorf("updater: no patch available from %s", currentSHA)
+	}
+
+	patchBody, err := u.Patch.FetchPatch(ctx, patch)
+	if err != nil {
+		return Info{}, err
+	}
+	defer patchBody.Close()
+
+	oldFile, err := os.Open(u.execPath)
+	if err != nil {
+		return Info{}, err
+	}
+	defer oldFile.Close()
+
+	newFile, err := ioutil.TempFile("", "updater-patch")
+	if err != nil {
+		return Info{}, err
+	}
+	tmpPath := newFile.Name()
+	defer os.Remove(tmpPath)
+	defer newFile.Close()
+
+	if err := binarydist.Patch(oldFile, newFile, patchBody); err != nil {
+		return Info{}, fmt.Errorf("updater: apply patch: %w", err)
+	}
+	newFile.Close()
+
+	sum, err := sha256File(tmpPath)
+	if err != nil {
+		return Info{}, err
+	}
+	if manifest.SHA256 != "" && sum != manifest.SHA256 {
+		return Info{}, fmt.Errorf("updater: patched binary hash mismatch: got %s want %s", sum, manifest.SHA256)
+	}
+
+	newPath := u.execPath + ".new"
+	if err := copyFile(tmpPath, newPath); err != nil {
+		return Info{}, err
+	}
+
+	return manifest, u.verifyStaged(newPath, &manifest)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return nil
+}
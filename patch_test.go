@@ -0,0 +1,141 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kr/binarydist"
+)
+
+// fakePatchStrategy serves a fixed Manifest and a canned patch body,
+// regardless of what PatchInfo is requested.
+type fakePatchStrategy struct {
+	manifest Info
+	patch    []byte
+}
+
+func (f *fakePatchStrategy) Manifest(ctx context.Context) (Info, error) {
+	return f.manifest, nil
+}
+
+func (f *fakePatchStrategy) FetchPatch(ctx context.Context, p PatchInfo) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.patch)), nil
+}
+
+// diffBytes produces a bsdiff patch from oldContent to newContent using the
+// same binarydist implementation patch.go applies updates with.
+func diffBytes(t *testing.T, oldContent, newContent string) []byte {
+	t.Helper()
+	var patch bytes.Buffer
+	if err := binarydist.Diff(bytes.NewReader([]byte(oldContent)), bytes.NewReader([]byte(newContent)), &patch); err != nil {
+		t.Fatalf("binarydist.Diff: %v", err)
+	}
+	return patch.Bytes()
+}
+
+func TestUpdateViaPatchFallsBackOnHashMismatch(t *testing.T) {
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "app")
+	if err := os.WriteFile(execPath, []byte("old-binary-content"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	currentSHA, err := sha256File(execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{
+		execPath: execPath,
+		Patch: &fakePatchStrategy{
+			manifest: Info{
+				Version: "1.2.3",
+				SHA256:  "not-the-real-hash",
+				Patches: map[string]PatchInfo{currentSHA: {}},
+			},
+			patch: diffBytes(t, "old-binary-content", "new-binary-content"),
+		},
+	}
+
+	if _, err := u.updateViaPatch(context.Background()); err == nil {
+		t.Fatal("updateViaPatch() = nil, want error on hash mismatch")
+	}
+
+	if _, statErr := os.Stat(execPath + ".new"); !os.IsNotExist(statErr) {
+		t.Fatalf("updateViaPatch left %s.new behind after a hash mismatch", execPath)
+	}
+}
+
+func TestUpdateViaPatchSucceedsOnMatchingHash(t *testing.T) {
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "app")
+	if err := os.WriteFile(execPath, []byte("old-binary-content"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	currentSHA, err := sha256File(execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSum, err := sha256Bytes(t, []byte("new-binary-content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := signInfo(t, priv, "1.2.3", wantSum)
+	manifest.Patches = map[string]PatchInfo{currentSHA: {}}
+
+	u := &Updater{
+		execPath:  execPath,
+		PublicKey: pub,
+		Patch: &fakePatchStrategy{
+			manifest: manifest,
+			patch:    diffBytes(t, "old-binary-content", "new-binary-content"),
+		},
+	}
+
+	info, err := u.updateViaPatch(context.Background())
+	if err != nil {
+		t.Fatalf("updateViaPatch() = %v, want nil", err)
+	}
+	if info.SHA256 != wantSum {
+		t.Fatalf("updateViaPatch() Info.SHA256 = %q, want %q", info.SHA256, wantSum)
+	}
+}
+
+func TestUpdateViaPatchFallsBackWhenNoPatchAvailable(t *testing.T) {
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "app")
+	if err := os.WriteFile(execPath, []byte("old-binary-content"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{
+		execPath: execPath,
+		Patch: &fakePatchStrategy{
+			manifest: Info{Version: "1.2.3"}, // no Patches entry for execPath's digest
+		},
+	}
+
+	if _, err := u.updateViaPatch(context.Background()); err == nil {
+		t.Fatal("updateViaPatch() = nil, want error when no patch matches the running binary")
+	}
+}
+
+func sha256Bytes(t *testing.T, b []byte) (string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "content")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return "", err
+	}
+	return sha256File(path)
+}
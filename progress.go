@@ -0,0 +1,121 @@
+package updater
+
+import (
+	"io"
+	"time"
+)
+
+// Stage identifies which phase of an update a Progress event describes.
+type Stage int
+
+const (
+	StageFetching Stage = iota
+	StageVerifying
+	StageUnpacking
+	StageStaging
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageFetching:
+		return "fetching"
+	case StageVerifying:
+		return "verifying"
+	case StageUnpacking:
+		return "unpacking"
+	case StageStaging:
+		return "staging"
+	default:
+		return "unknown"
+	}
+}
+
+// Progress describes how far along an update is. BytesTotal is 0 when the
+// total size isn't known in advance (e.g. a Fetcher that doesn't report
+// Content-Length).
+type Progress struct {
+	BytesDone  int64
+	BytesTotal int64
+	Stage      Stage
+	Speed      float64 // bytes per second, averaged since the previous event
+}
+
+// ProgressFunc receives Progress events during StartUpdater. It's called
+// from its own goroutine, so a slow ProgressFunc doesn't stall the
+// download: reportProgress drops an event rather than wait on it.
+type ProgressFunc func(Progress)
+
+// progressInterval and progressByteStep bound how chatty progress reporting
+// gets: an event fires once at least one has elapsed, whichever first.
+const (
+	progressInterval = 250 * time.Millisecond
+	progressByteStep = 256 * 1024
+)
+
+// reportProgress delivers p to u.OnProgress without blocking the caller. If
+// a previous event is still queued, p is dropped in its favor rather than
+// stalling the download.
+func (u *Updater) reportProgress(p Progress) {
+	if u.OnProgress == nil {
+		return
+	}
+	u.progressOnce.Do(func() { go u.runProgressWorker() })
+	select {
+	case u.progressCh <- p:
+	default:
+	}
+}
+
+// sizer is implemented by the read-closers Fetcher implementations return
+// when they know the total download size up front (e.g. from
+// Content-Length), letting progress events report a meaningful BytesTotal.
+type sizer interface {
+	Size() int64
+}
+
+// countingReader wraps a Reader, emitting debounced Progress events as it's
+// read through.
+type countingReader struct {
+	io.Reader
+	u     *Updater
+	stage Stage
+	total int64
+
+	done         int64
+	lastReportAt time.Time
+	lastReported int64
+}
+
+func newCountingReader(u *Updater, r io.Reader, stage Stage, total int64) *countingReader {
+	return &countingReader{
+		Reader:       r,
+		u:            u,
+		stage:        stage,
+		total:        total,
+		lastReportAt: time.Now(),
+	}
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.done += int64(n)
+
+	now := time.Now()
+	sinceBytes := c.done - c.lastReported
+	sinceTime := now.Sub(c.lastReportAt)
+	if n > 0 && (sinceBytes >= progressByteStep || sinceTime >= progressInterval) {
+		var speed float64
+		if sinceTime > 0 {
+			speed = float64(sinceBytes) / sinceTime.Seconds()
+		}
+		c.u.reportProgress(Progress{
+			BytesDone:  c.done,
+			BytesTotal: c.total,
+			Stage:      c.stage,
+			Speed:      speed,
+		})
+		c.lastReportAt = now
+		c.lastReported = c.done
+	}
+	return n, err
+}
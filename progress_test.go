@@ -0,0 +1,48 @@
+package updater
+
+import (
+	"strings"
+	"testing"
+)
+
+// readAll drains r the way io.Copy would, in small chunks so the debounce
+// bookkeeping in countingReader.Read sees more than one call.
+func readAll(t *testing.T, r *countingReader) {
+	t.Helper()
+	buf := make([]byte, 64)
+	for {
+		_, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestCountingReaderDoesNotReportBelowByteStep(t *testing.T) {
+	u := &Updater{}
+	body := strings.NewReader(strings.Repeat("x", progressByteStep-1))
+	r := newCountingReader(u, body, StageFetching, int64(body.Len()))
+
+	readAll(t, r)
+
+	// A read this small, finishing well inside progressInterval, must
+	// never have crossed either debounce threshold.
+	if r.lastReported != 0 {
+		t.Fatalf("lastReported = %d, want 0 for a read under progressByteStep", r.lastReported)
+	}
+}
+
+func TestCountingReaderReportsOnceByteStepIsCrossed(t *testing.T) {
+	u := &Updater{}
+	total := progressByteStep + 64
+	body := strings.NewReader(strings.Repeat("x", total))
+	r := newCountingReader(u, body, StageFetching, int64(total))
+
+	readAll(t, r)
+
+	// The debounce fires as soon as progressByteStep is crossed, not
+	// necessarily at the very last byte of the stream.
+	if r.lastReported < progressByteStep {
+		t.Fatalf("lastReported = %d, want at least progressByteStep (%d)", r.lastReported, progressByteStep)
+	}
+}
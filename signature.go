@@ -0,0 +1,67 @@
+package updater
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// SignatureAlgorithmEd25519 is the only signature algorithm Updater
+// currently understands.
+const SignatureAlgorithmEd25519 = "ed25519"
+
+// verifySignature checks that info.Signature is a valid signature over
+// sha256(binary) || version, using PublicKey or any key in Keyring.
+// Verification is mandatory: an Updater with no trusted keys configured
+// rejects every update rather than silently skipping the check.
+func (u *Updater) verifySignature(info Info, binarySHA256Hex string) error {
+	keys := u.trustedKeys()
+	if len(keys) == 0 {
+		return errors.New("updater: signature verification is mandatory; set Updater.PublicKey or Keyring")
+	}
+
+	algo := info.SignatureAlgorithm
+	if algo == "" {
+		algo = SignatureAlgorithmEd25519
+	}
+	if algo != SignatureAlgorithmEd25519 {
+		return fmt.Errorf("updater: unsupported signature algorithm %q", algo)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(info.Signature)
+	if err != nil {
+		return fmt.Errorf("updater: decode signature: %w", err)
+	}
+
+	digest, err := hex.DecodeString(binarySHA256Hex)
+	if err != nil {
+		return err
+	}
+	message := append(digest, []byte(info.Version)...)
+
+	for _, key := range keys {
+		// ed25519.Verify panics on a key of the wrong length instead of
+		// just failing, so a single malformed or misconfigured key must
+		// not reach it.
+		if len(key) != ed25519.PublicKeySize {
+			continue
+		}
+		if ed25519.Verify(key, message, sig) {
+			return nil
+		}
+	}
+	return errors.New("updater: signature verification failed")
+}
+
+// trustedKeys returns every key an update may be signed with: PublicKey
+// plus Keyring, so operators can rotate keys without breaking clients that
+// still trust the old one.
+func (u *Updater) trustedKeys() []ed25519.PublicKey {
+	keys := make([]ed25519.PublicKey, 0, len(u.Keyring)+1)
+	if u.PublicKey != nil {
+		keys = append(keys, u.PublicKey)
+	}
+	return append(keys, u.Keyring...)
+}
@@ -0,0 +1,61 @@
+package updater
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func signInfo(t *testing.T, priv ed25519.PrivateKey, version, sha256Hex string) Info {
+	t.Helper()
+	digest, err := hex.DecodeString(sha256Hex)
+	if err != nil {
+		t.Fatalf("decode hex %q: %v", sha256Hex, err)
+	}
+	message := append(append([]byte{}, digest...), []byte(version)...)
+	sig := ed25519.Sign(priv, message)
+	return Info{
+		Version:   version,
+		SHA256:    sha256Hex,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}
+}
+
+func TestVerifySignatureRejectsUndersizedKeys(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const sha = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	info := signInfo(t, priv, "1.2.3", sha)
+
+	u := &Updater{
+		// A malformed key (wrong length) must be skipped, not panic, and
+		// the valid key that follows it must still verify.
+		Keyring: []ed25519.PublicKey{[]byte("too-short"), pub},
+	}
+
+	if err := u.verifySignature(info, sha); err != nil {
+		t.Fatalf("verifySignature() = %v, want nil", err)
+	}
+}
+
+func TestVerifySignatureFailsClosedOnAllMalformedKeys(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const sha = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	info := signInfo(t, priv, "1.2.3", sha)
+
+	u := &Updater{
+		PublicKey: []byte("not-a-valid-length-key"),
+	}
+
+	if err := u.verifySignature(info, sha); err == nil {
+		t.Fatal("verifySignature() = nil, want error for malformed key")
+	}
+}
@@ -0,0 +1,101 @@
+//go:build !windows
+
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// installStaged installs <execPath>.new in place of the running binary,
+// keeping the previous binary at <execPath>.old so a failed restart can
+// still be recovered from; a stale .old left behind by an aborted previous
+// attempt is removed first. It only touches files on disk: the calling
+// process keeps running its already-loaded image until it re-execs or a
+// new process is spawned.
+func (u *Updater) installStaged() error {
+	os.Remove(u.execPath + ".old")
+
+	if err := os.Rename(u.execPath, u.execPath+".old"); err != nil {
+		return err
+	}
+
+	newPath := u.execPath + ".new"
+	if _, err := os.Stat(newPath); err == nil {
+		if err := os.Rename(newPath, u.execPath); err != nil {
+			os.Rename(u.execPath+".old", u.execPath)
+			return err
+		}
+	} else if err := os.Rename(u.execPath+".old", u.execPath); err != nil {
+		return err
+	}
+	return nil
+}
+
+// swapAndRestart installs the staged binary and re-execs it. If
+// RollbackOnFailure is set, the previous binary is kept at <execPath>.old
+// until the new one proves itself; otherwise .old is removed right before
+// the re-exec.
+func (u *Updater) swapAndRestart() error {
+	if err := u.installStaged(); err != nil {
+		return err
+	}
+
+	if u.RollbackOnFailure <= 0 {
+		os.Remove(u.execPath + ".old")
+		return syscall.Exec(u.execPath, os.Args, os.Environ())
+	}
+
+	return u.startWithRollback()
+}
+
+// startWithRollback launches the freshly swapped-in binary as a child and
+// watches it for RollbackOnFailure; if it exits non-zero within that
+// window, the previous binary is restored and re-exec'd instead.
+func (u *Updater) startWithRollback() error {
+	proc, err := os.StartProcess(u.execPath, os.Args, &os.ProcAttr{
+		Dir:   filepath.Dir(u.execPath),
+		Env:   os.Environ(),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys:   &syscall.SysProcAttr{},
+	})
+	if err != nil {
+		return err
+	}
+
+	stateCh := make(chan *os.ProcessState, 1)
+	go func() {
+		state, _ := proc.Wait()
+		stateCh <- state
+	}()
+
+	select {
+	case state := <-stateCh:
+		if state != nil && state.Success() {
+			os.Remove(u.execPath + ".old")
+			return nil
+		}
+		if err := u.rollback(); err != nil {
+			return err
+		}
+		return syscall.Exec(u.execPath, os.Args, os.Environ())
+
+	case <-time.After(u.RollbackOnFailure):
+		os.Remove(u.execPath + ".old")
+		old, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			return err
+		}
+		return old.Kill()
+	}
+}
+
+// rollback restores the binary that was running before this restart.
+func (u *Updater) rollback() error {
+	if err := os.Remove(u.execPath); err != nil {
+		return err
+	}
+	return os.Rename(u.execPath+".old", u.execPath)
+}
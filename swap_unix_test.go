@@ -0,0 +1,89 @@
+//go:build !windows
+
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallStagedSwapsNewBinaryIntoPlace(t *testing.T) {
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "app")
+	if err := os.WriteFile(execPath, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(execPath+".new", []byte("new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{execPath: execPath}
+	if err := u.installStaged(); err != nil {
+		t.Fatalf("installStaged() = %v, want nil", err)
+	}
+
+	got, err := os.ReadFile(execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Fatalf("execPath content = %q, want %q", got, "new")
+	}
+
+	old, err := os.ReadFile(execPath + ".old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(old) != "old" {
+		t.Fatalf(".old content = %q, want %q", old, "old")
+	}
+}
+
+func TestInstallStagedRemovesStalePreviousOld(t *testing.T) {
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "app")
+	if err := os.WriteFile(execPath, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(execPath+".new", []byte("new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(execPath+".old", []byte("stale-from-aborted-attempt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{execPath: execPath}
+	if err := u.installStaged(); err != nil {
+		t.Fatalf("installStaged() = %v, want nil", err)
+	}
+
+	old, err := os.ReadFile(execPath + ".old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(old) != "old" {
+		t.Fatalf(".old content = %q, want the binary replaced by this run, %q", old, "old")
+	}
+}
+
+func TestInstallStagedWithoutNewIsANoop(t *testing.T) {
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "app")
+	if err := os.WriteFile(execPath, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Updater{execPath: execPath}
+	if err := u.installStaged(); err != nil {
+		t.Fatalf("installStaged() = %v, want nil when no .new is staged", err)
+	}
+
+	got, err := os.ReadFile(execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Fatalf("execPath content = %q, want unchanged %q", got, "old")
+	}
+}
@@ -0,0 +1,128 @@
+//go:build windows
+
+package updater
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// installStaged performs the documented Windows self-update dance: the
+// running .exe can't be overwritten while it's mapped in, so the old
+// binary is moved aside and its cleanup is scheduled for next reboot, and
+// the new one takes its place. It only touches files on disk: the calling
+// process keeps running its already-loaded image until it's relaunched.
+func (u *Updater) installStaged() error {
+	oldPath := u.execPath + ".old"
+	newPath := u.execPath + ".new"
+
+	if err := moveFileEx(u.execPath, oldPath, windows.MOVEFILE_REPLACE_EXISTING); err != nil {
+		return fmt.Errorf("updater: move running binary aside: %w", err)
+	}
+	if err := moveFileEx(newPath, u.execPath, windows.MOVEFILE_REPLACE_EXISTING); err != nil {
+		moveFileEx(oldPath, u.execPath, windows.MOVEFILE_REPLACE_EXISTING)
+		return fmt.Errorf("updater: install new binary: %w", err)
+	}
+
+	// The old image is still locked by this process; ask Windows to
+	// delete it at the next reboot.
+	moveFileEx(oldPath, "", windows.MOVEFILE_DELAY_UNTIL_REBOOT)
+	return nil
+}
+
+// swapAndRestart installs the staged binary and either spawns a fresh
+// process or, if ServiceName is set, restarts the service through the
+// service control manager so Windows supervises the relaunch.
+func (u *Updater) swapAndRestart() error {
+	if err := u.installStaged(); err != nil {
+		return err
+	}
+
+	if u.ServiceName != "" {
+		return restartService(u.ServiceName)
+	}
+
+	cmd := exec.Command(u.execPath, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		moveFileEx(u.execPath+".old", u.execPath, windows.MOVEFILE_REPLACE_EXISTING)
+		return err
+	}
+
+	if u.RollbackOnFailure > 0 {
+		go watchForRollback(u, cmd)
+		return nil
+	}
+
+	os.Exit(0)
+	return nil
+}
+
+// watchForRollback waits for cmd to exit; if it does so non-zero within
+// RollbackOnFailure, the previous binary is restored and relaunched.
+func watchForRollback(u *Updater, cmd *exec.Cmd) {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			os.Exit(0)
+		}
+		moveFileEx(u.execPath, "", windows.MOVEFILE_DELAY_UNTIL_REBOOT)
+		moveFileEx(u.execPath+".old", u.execPath, windows.MOVEFILE_REPLACE_EXISTING)
+		exec.Command(u.execPath, os.Args[1:]...).Start()
+		os.Exit(1)
+	case <-time.After(u.RollbackOnFailure):
+		os.Exit(0)
+	}
+}
+
+func moveFileEx(from, to string, flags uint32) error {
+	fromPtr, err := windows.UTF16PtrFromString(from)
+	if err != nil {
+		return err
+	}
+
+	var toPtr *uint16
+	if to != "" {
+		toPtr, err = windows.UTF16PtrFromString(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	return windows.MoveFileEx(fromPtr, toPtr, flags)
+}
+
+// restartService stops and starts name via the service control manager,
+// rather than killing the process directly, so Windows supervises it.
+func restartService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if _, err := s.Control(svc.Stop); err != nil {
+		return fmt.Errorf("updater: stop service %s: %w", name, err)
+	}
+
+	// Give the SCM a moment to tear the old instance down before
+	// starting the updated binary back up.
+	time.Sleep(500 * time.Millisecond)
+
+	return s.Start()
+}
@@ -3,70 +3,126 @@ package updater
 import (
 	"archive/zip"
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
-	"syscall"
+	"sync"
 	"time"
 )
 
 // Updater provides a configuration on how to update
 type Updater struct {
-	upstream string
-	interval time.Duration
+	fetcher  Fetcher
 	execPath string
+
+	// Patch, if set, makes update() try a binary diff before falling back
+	// to a full download from fetcher.
+	Patch PatchStrategy
+
+	// PublicKey and Keyring are the Ed25519 keys trusted to sign updates.
+	// At least one of them must be set, or update() refuses to stage any
+	// binary. Keyring lets operators rotate keys: old and new keys can
+	// both verify until every client has seen the new one.
+	PublicKey ed25519.PublicKey
+	Keyring   []ed25519.PublicKey
+
+	// VerifyOnly downloads and verifies updates but removes <execPath>.new
+	// again after verifying it instead of leaving it staged, so operators
+	// can audit a release before it's allowed to actually swap in.
+	VerifyOnly bool
+
+	// OnProgress, if set, is called with Progress events as an update
+	// downloads, verifies, and stages. See reportProgress for delivery
+	// semantics.
+	OnProgress   ProgressFunc
+	progressCh   chan Progress
+	progressOnce sync.Once
+
+	// RollbackOnFailure, if non-zero, makes RestartAndUpdate watch the
+	// freshly swapped-in process for this long; if it exits non-zero
+	// within that window, the previous binary is restored and restarted
+	// instead. It has no effect when this process was started via Run:
+	// RestartAndUpdate then removes <execPath>.old and hands the restart to
+	// the supervisor.
+	RollbackOnFailure time.Duration
+
+	// ServiceName, on Windows, causes RestartAndUpdate to restart the
+	// named service via the service control manager instead of spawning
+	// a bare process. It's ignored on other platforms.
+	ServiceName string
+
+	// Cache avoids redundant downloads across ticks. NewUpdater sets it to
+	// a DirCache under os.UserCacheDir() and wires it into fetcher if
+	// fetcher implements CacheAware (a fetcher that already has a Cache
+	// keeps its own). Only NewUpdater reads this field: assigning a Cache
+	// to it afterwards does not change the Cache fetcher uses.
+	Cache Cache
 }
 
-// NewUpdater creates Updater with upstream to be pointing to a url to get the
-// newest binary file. interval sets how frequent the polling
-func NewUpdater(upstream string, interval time.Duration) (*Updater, error) {
+// NewUpdater creates an Updater that pulls new releases from fetcher.
+func NewUpdater(fetcher Fetcher) (*Updater, error) {
 	execPath, err := os.Executable()
 	if err != nil {
 		return nil, err
 	}
 
 	updater := Updater{
-		upstream: upstream,
-		interval: interval,
-		execPath: execPath,
+		fetcher:    fetcher,
+		execPath:   execPath,
+		progressCh: make(chan Progress, 1),
+	}
+
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		appName := filepath.Base(execPath)
+		if dc, err := NewDirCache(filepath.Join(cacheDir, appName, "updates")); err == nil {
+			updater.Cache = dc
+		}
+	}
+	if ca, ok := fetcher.(CacheAware); ok && updater.Cache != nil {
+		ca.UseCache(updater.Cache)
 	}
 
 	return &updater, nil
 }
 
-// RestartAndUpdate swaps the old process with a new process
-func (u *Updater) RestartAndUpdate() error {
-	var err error
-	if err = os.Rename(u.execPath, u.execPath+".old"); err != nil {
-		return err
-	}
-
-	_, err = os.StartProcess(u.execPath, os.Args, &os.ProcAttr{
-		Dir:   filepath.Dir(u.execPath),
-		Env:   os.Environ(),
-		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
-		Sys:   &syscall.SysProcAttr{},
-	})
-	if err != nil {
-		return err
+// runProgressWorker forwards queued Progress events to OnProgress for the
+// lifetime of the Updater. It's started lazily, on the first reported
+// Progress event, so an Updater that never sets OnProgress doesn't leak a
+// goroutine.
+func (u *Updater) runProgressWorker() {
+	for p := range u.progressCh {
+		if u.OnProgress != nil {
+			u.OnProgress(p)
+		}
 	}
+}
 
-	old, err := os.FindProcess(os.Getpid())
-	if err != nil {
-		return err
+// RestartAndUpdate installs the staged binary (see installStaged in
+// swap_unix.go and swap_windows.go), then swaps the old process with a new
+// one. If this process was started via Run, it asks the supervisor for a
+// graceful restart so in-flight connections can be handed off; the
+// supervisor re-execs whatever is now at execPath, which is the freshly
+// installed binary. Otherwise it performs a full platform-appropriate swap
+// and restart: see swapAndRestart.
+func (u *Updater) RestartAndUpdate() error {
+	if currentState != nil {
+		if err := u.installStaged(); err != nil {
+			return err
+		}
+		os.Remove(u.execPath + ".old")
+		return currentState.Restart()
 	}
-
-	return old.Kill()
+	return u.swapAndRestart()
 }
 
 // StartUpdater will start updating within intervals and keep sending
 // the newest info
 func (u *Updater) StartUpdater(ctx context.Context) <-chan Info {
-	ticker := time.NewTicker(u.interval)
+	ticker := time.NewTicker(u.fetcher.Interval())
 	infoChan := make(chan Info)
 
 	go func() {
@@ -74,8 +130,11 @@ func (u *Updater) StartUpdater(ctx context.Context) <-chan Info {
 		for {
 			select {
 			case <-ticker.C:
-				info, err := u.update()
+				info, err := u.update(ctx)
 				if err != nil {
+					// ErrNotModified just means there's nothing new to
+					// report this tick; any other error is swallowed the
+					// same way until the next tick.
 					continue
 				}
 				infoChan <- info
@@ -88,8 +147,16 @@ func (u *Updater) StartUpdater(ctx context.Context) <-chan Info {
 }
 
 // update fetches and unpack to <execPath>.new
-func (u *Updater) update() (Info, error) {
-	archivePath, err := u.fetch()
+func (u *Updater) update(ctx context.Context) (Info, error) {
+	if u.Patch != nil {
+		if info, err := u.updateViaPatch(ctx); err == nil {
+			return info, nil
+		}
+		// Any patch error (no patch available, download failure, hash
+		// mismatch) falls back to a full download below.
+	}
+
+	archivePath, err := u.fetch(ctx)
 	if err != nil {
 		return Info{}, err
 	}
@@ -97,13 +164,19 @@ func (u *Updater) update() (Info, error) {
 	return u.unpack(archivePath)
 }
 
-// fetch downloads the newest archive and returns the path to where it's saved
-func (u *Updater) fetch() (string, error) {
-	resp, err := http.Get(u.upstream)
+// fetch downloads the newest archive via u.fetcher and returns the path to
+// where it's saved
+func (u *Updater) fetch(ctx context.Context) (string, error) {
+	body, err := u.fetcher.Fetch(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
+
+	var total int64
+	if s, ok := body.(sizer); ok {
+		total = s.Size()
+	}
 
 	tmp, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -112,7 +185,7 @@ func (u *Updater) fetch() (string, error) {
 	defer tmp.Close()
 
 	// Download an archive
-	_, err = io.Copy(tmp, resp.Body)
+	_, err = io.Copy(tmp, newCountingReader(u, body, StageFetching, total))
 	return tmp.Name(), err
 }
 
@@ -121,6 +194,7 @@ func (u *Updater) fetch() (string, error) {
 func (u *Updater) unpack(path string) (Info, error) {
 	var info Info
 	var binaryFile, infoFile io.ReadCloser
+	var binarySize int64
 
 	r, err := zip.OpenReader(path)
 	if err != nil {
@@ -140,6 +214,7 @@ func (u *Updater) unpack(path string) (Info, error) {
 			infoFile = rc
 		case u.execPath:
 			binaryFile = rc
+			binarySize = int64(f.UncompressedSize64)
 		}
 	}
 
@@ -155,12 +230,48 @@ func (u *Updater) unpack(path string) (Info, error) {
 		return info, err
 	}
 
-	newFile, err := os.Create(u.execPath + ".new")
+	newPath := u.execPath + ".new"
+	newFile, err := os.Create(newPath)
 	if err != nil {
 		return info, err
 	}
-	defer newFile.Close()
 
-	_, err = io.Copy(newFile, binaryFile)
-	return info, err
+	reader := newCountingReader(u, binaryFile, StageUnpacking, binarySize)
+	if _, err = io.Copy(newFile, reader); err != nil {
+		newFile.Close()
+		os.Remove(newPath)
+		return info, err
+	}
+	newFile.Close()
+
+	return info, u.verifyStaged(newPath, &info)
+}
+
+// verifyStaged hashes the binary staged at newPath, verifies its signature,
+// and removes it on any failure (or when VerifyOnly is set, even on
+// success).
+func (u *Updater) verifyStaged(newPath string, info *Info) error {
+	u.reportProgress(Progress{Stage: StageVerifying})
+
+	sum, err := sha256File(newPath)
+	if err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	info.SHA256 = sum
+
+	if err := u.verifySignature(*info, sum); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+
+	if u.VerifyOnly {
+		os.Remove(newPath)
+		return nil
+	}
+
+	if fi, err := os.Stat(newPath); err == nil {
+		u.reportProgress(Progress{Stage: StageStaging, BytesDone: fi.Size(), BytesTotal: fi.Size()})
+	}
+	return nil
 }